@@ -0,0 +1,55 @@
+package cluster
+
+import "fmt"
+
+// Cluster is a node's view of the sharded deployment it belongs to: its own
+// identity plus the slot topology computed from the full peer list.
+type Cluster struct {
+	self     Node
+	topology *Topology
+}
+
+// New builds a Cluster for a node listening on selfAddr alongside peerAddrs
+// (the other nodes' addresses, not including selfAddr). Node IDs are the
+// addresses themselves -- this server has no separate node-ID concept or
+// gossip protocol, so the listen address doubles as the identity.
+func New(selfAddr string, peerAddrs []string) (*Cluster, error) {
+	if selfAddr == "" {
+		return nil, fmt.Errorf("cluster: selfAddr must not be empty")
+	}
+
+	seen := map[string]bool{selfAddr: true}
+	nodes := []Node{{ID: selfAddr, Addr: selfAddr}}
+	for _, addr := range peerAddrs {
+		if addr == "" || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		nodes = append(nodes, Node{ID: addr, Addr: addr})
+	}
+
+	return &Cluster{
+		self:     nodes[0],
+		topology: NewTopology(nodes),
+	}, nil
+}
+
+// Self returns this node's identity.
+func (c *Cluster) Self() Node {
+	return c.self
+}
+
+// Topology returns the cluster's slot topology.
+func (c *Cluster) Topology() *Topology {
+	return c.topology
+}
+
+// OwnerOf returns the node that owns key's slot.
+func (c *Cluster) OwnerOf(key string) Node {
+	return c.topology.Owner(KeySlot(key))
+}
+
+// OwnsLocally reports whether this node owns key's slot.
+func (c *Cluster) OwnsLocally(key string) bool {
+	return c.OwnerOf(key).ID == c.self.ID
+}
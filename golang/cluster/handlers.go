@@ -0,0 +1,102 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/afoley587/redis-lite/resp"
+	"github.com/afoley587/redis-lite/store"
+)
+
+// RegisterHandlers wires the CLUSTER command into store.Handlers. It follows
+// the same pattern persistence uses for BGREWRITEAOF: the subsystem owns its
+// handler and closes over its own state rather than store reaching back into
+// cluster.
+func (c *Cluster) RegisterHandlers() {
+	store.Handlers["CLUSTER"] = c.cluster
+}
+
+func (c *Cluster) cluster(conn resp.Conn, cmd resp.Command) {
+	args := cmd.Args[1:]
+	if len(args) == 0 {
+		conn.WriteError("ERR wrong number of arguments for 'CLUSTER'")
+		return
+	}
+
+	switch strings.ToUpper(string(args[0])) {
+	case "SLOTS":
+		c.writeSlots(conn)
+	case "NODES":
+		conn.WriteBulk([]byte(c.nodesLine()))
+	case "KEYSLOT":
+		if len(args) != 2 {
+			conn.WriteError("ERR wrong number of arguments for 'CLUSTER KEYSLOT'")
+			return
+		}
+		conn.WriteInt(KeySlot(string(args[1])))
+	case "COUNTKEYSINSLOT":
+		if len(args) != 2 {
+			conn.WriteError("ERR wrong number of arguments for 'CLUSTER COUNTKEYSINSLOT'")
+			return
+		}
+		slot, err := strconv.Atoi(string(args[1]))
+		if err != nil {
+			conn.WriteError("ERR invalid slot")
+			return
+		}
+		conn.WriteInt(c.countKeysInSlot(slot))
+	default:
+		conn.WriteError("ERR unknown CLUSTER subcommand")
+	}
+}
+
+// writeSlots implements CLUSTER SLOTS: an array of
+// [start, end, [host, port]] entries, one per node range.
+func (c *Cluster) writeSlots(conn resp.Conn) {
+	ranges := c.topology.Ranges()
+	b := resp.NewReplyBuilder(conn.Proto())
+	b.WriteArray(len(ranges))
+	for _, r := range ranges {
+		b.WriteArray(3)
+		b.WriteInt(r.Start)
+		b.WriteInt(r.End)
+
+		b.WriteArray(2)
+		host, port, err := net.SplitHostPort(r.Node.Addr)
+		if err != nil {
+			host, port = r.Node.Addr, "0"
+		}
+		b.WriteBulk([]byte(host))
+		portNum, _ := strconv.Atoi(port)
+		b.WriteInt(portNum)
+	}
+	b.Flush(conn)
+}
+
+// nodesLine implements CLUSTER NODES: one line per node in the
+// "id addr flags master - ping pong epoch state slots" format real Redis
+// uses, trimmed to the fields this server can actually populate.
+func (c *Cluster) nodesLine() string {
+	var b strings.Builder
+	for _, r := range c.topology.Ranges() {
+		flags := "master"
+		if r.Node.ID == c.self.ID {
+			flags += ",myself"
+		}
+		fmt.Fprintf(&b, "%s %s %s - 0 0 0 connected %d-%d\n",
+			r.Node.ID, r.Node.Addr, flags, r.Start, r.End)
+	}
+	return b.String()
+}
+
+func (c *Cluster) countKeysInSlot(slot int) int {
+	count := 0
+	for key := range store.Snapshot() {
+		if KeySlot(key) == slot {
+			count++
+		}
+	}
+	return count
+}
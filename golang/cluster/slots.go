@@ -0,0 +1,50 @@
+package cluster
+
+import "strings"
+
+// NumSlots is the size of the Redis Cluster keyspace.
+const NumSlots = 16384
+
+// KeySlot returns the cluster slot for key: CRC16(key) mod NumSlots,
+// honoring {hash-tag} semantics -- when key contains a non-empty `{...}`,
+// only the bytes between the first '{' and the next '}' are hashed, so
+// related keys can be forced onto the same slot (and therefore the same
+// node).
+func KeySlot(key string) int {
+	return int(crc16([]byte(hashTag(key))) % NumSlots)
+}
+
+func hashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start == -1 {
+		return key
+	}
+
+	end := strings.IndexByte(key[start+1:], '}')
+	if end == -1 {
+		return key
+	}
+	if end == 0 {
+		// "{}" isn't a tag -- fall back to hashing the whole key.
+		return key
+	}
+
+	return key[start+1 : start+1+end]
+}
+
+// KeyOf reports the key argument for commandName given its args (excluding
+// the command name itself), if the command addresses a single key. Only
+// single-key commands participate in slot routing today; DEL's keys beyond
+// the first aren't cross-slot checked (a real CROSSSLOT error is future
+// work).
+func KeyOf(commandName string, args [][]byte) (string, bool) {
+	switch commandName {
+	case "GET", "SET", "DEL":
+		if len(args) == 0 {
+			return "", false
+		}
+		return string(args[0]), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,47 @@
+package cluster
+
+import "testing"
+
+func TestKeySlotHashTag(t *testing.T) {
+	// Keys sharing a {tag} must land on the same slot regardless of what
+	// else surrounds the tag.
+	a := KeySlot("{user1000}.following")
+	b := KeySlot("{user1000}.followers")
+	if a != b {
+		t.Fatalf("tagged keys landed on different slots: %d vs %d", a, b)
+	}
+
+	// An empty "{}" isn't a tag -- it should hash the whole key, not collide
+	// with every other key that happens to contain "{}".
+	if KeySlot("{}") == KeySlot("other{}") {
+		t.Fatalf("empty hash tag incorrectly forced two distinct keys onto the same slot")
+	}
+
+	// A key with no braces at all just hashes itself.
+	if got := KeySlot("foo"); got < 0 || got >= NumSlots {
+		t.Fatalf("KeySlot(%q) = %d, want in [0, %d)", "foo", got, NumSlots)
+	}
+}
+
+func TestKeyOf(t *testing.T) {
+	tests := []struct {
+		command string
+		args    [][]byte
+		wantKey string
+		wantOK  bool
+	}{
+		{"GET", [][]byte{[]byte("foo")}, "foo", true},
+		{"SET", [][]byte{[]byte("foo"), []byte("bar")}, "foo", true},
+		{"DEL", [][]byte{[]byte("foo")}, "foo", true},
+		{"GET", nil, "", false},
+		{"PING", [][]byte{[]byte("foo")}, "", false},
+	}
+
+	for _, tt := range tests {
+		key, ok := KeyOf(tt.command, tt.args)
+		if key != tt.wantKey || ok != tt.wantOK {
+			t.Errorf("KeyOf(%q, %v) = (%q, %v), want (%q, %v)",
+				tt.command, tt.args, key, ok, tt.wantKey, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package cluster
+
+import (
+	"hash/crc32"
+	"sort"
+)
+
+// Node identifies a single cluster member.
+type Node struct {
+	ID   string
+	Addr string
+}
+
+// NodeRange is a contiguous span of slots owned by a Node, as reported by
+// CLUSTER SLOTS/NODES.
+type NodeRange struct {
+	Start int
+	End   int
+	Node  Node
+}
+
+// Topology maps the slot keyspace onto a fixed set of nodes.
+//
+// Node IDs are hashed onto the slot space and sorted, then each node is
+// given the contiguous range of slots up to (but not including) the next
+// node's position -- the last node absorbs the remainder through NumSlots-1.
+// This is a deliberate simplification of textbook ring-based consistent
+// hashing: no virtual nodes and no wraparound, traded for the simple
+// contiguous-range-per-node property CLUSTER SLOTS wants to report. It's
+// good enough for a fixed, rarely-resharded node set; it is not meant to
+// minimize slot movement on membership changes.
+type Topology struct {
+	ranges []NodeRange
+}
+
+// NewTopology builds a Topology over nodes. nodes must be non-empty.
+func NewTopology(nodes []Node) *Topology {
+	ordered := make([]Node, len(nodes))
+	copy(ordered, nodes)
+	sort.Slice(ordered, func(i, j int) bool {
+		return slotPosition(ordered[i].ID) < slotPosition(ordered[j].ID)
+	})
+
+	ranges := make([]NodeRange, len(ordered))
+	start := 0
+	for i, node := range ordered {
+		end := NumSlots - 1
+		if i < len(ordered)-1 {
+			end = slotPosition(ordered[i+1].ID) - 1
+			if end < start {
+				end = start
+			}
+		}
+		ranges[i] = NodeRange{Start: start, End: end, Node: node}
+		start = end + 1
+	}
+
+	return &Topology{ranges: ranges}
+}
+
+func slotPosition(nodeID string) int {
+	return int(crc32.ChecksumIEEE([]byte(nodeID)) % NumSlots)
+}
+
+// Owner returns the Node responsible for slot.
+func (t *Topology) Owner(slot int) Node {
+	for _, r := range t.ranges {
+		if slot >= r.Start && slot <= r.End {
+			return r.Node
+		}
+	}
+	return t.ranges[len(t.ranges)-1].Node
+}
+
+// Range returns the slot range owned by nodeID.
+func (t *Topology) Range(nodeID string) (start, end int, ok bool) {
+	for _, r := range t.ranges {
+		if r.Node.ID == nodeID {
+			return r.Start, r.End, true
+		}
+	}
+	return 0, 0, false
+}
+
+// Ranges returns every node's slot range, ordered by start slot.
+func (t *Topology) Ranges() []NodeRange {
+	out := make([]NodeRange, len(t.ranges))
+	copy(out, t.ranges)
+	return out
+}
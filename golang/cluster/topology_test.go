@@ -0,0 +1,51 @@
+package cluster
+
+import "testing"
+
+func TestTopologyRangesCoverKeyspaceContiguously(t *testing.T) {
+	nodes := []Node{{ID: "a", Addr: "a:1"}, {ID: "b", Addr: "b:1"}, {ID: "c", Addr: "c:1"}}
+	topo := NewTopology(nodes)
+
+	ranges := topo.Ranges()
+	if len(ranges) != len(nodes) {
+		t.Fatalf("got %d ranges, want %d", len(ranges), len(nodes))
+	}
+
+	if ranges[0].Start != 0 {
+		t.Errorf("first range starts at %d, want 0", ranges[0].Start)
+	}
+	if ranges[len(ranges)-1].End != NumSlots-1 {
+		t.Errorf("last range ends at %d, want %d", ranges[len(ranges)-1].End, NumSlots-1)
+	}
+	for i := 1; i < len(ranges); i++ {
+		if ranges[i].Start != ranges[i-1].End+1 {
+			t.Errorf("range %d starts at %d, want %d (contiguous with previous range's end)",
+				i, ranges[i].Start, ranges[i-1].End+1)
+		}
+	}
+}
+
+func TestTopologyOwnerAtRangeBoundaries(t *testing.T) {
+	nodes := []Node{{ID: "a", Addr: "a:1"}, {ID: "b", Addr: "b:1"}}
+	topo := NewTopology(nodes)
+
+	for _, r := range topo.Ranges() {
+		if owner := topo.Owner(r.Start); owner.ID != r.Node.ID {
+			t.Errorf("Owner(%d) = %s, want %s (range start)", r.Start, owner.ID, r.Node.ID)
+		}
+		if owner := topo.Owner(r.End); owner.ID != r.Node.ID {
+			t.Errorf("Owner(%d) = %s, want %s (range end)", r.End, owner.ID, r.Node.ID)
+		}
+	}
+}
+
+func TestTopologySingleNodeOwnsEverything(t *testing.T) {
+	topo := NewTopology([]Node{{ID: "solo", Addr: "solo:1"}})
+
+	if owner := topo.Owner(0); owner.ID != "solo" {
+		t.Errorf("Owner(0) = %s, want solo", owner.ID)
+	}
+	if owner := topo.Owner(NumSlots - 1); owner.ID != "solo" {
+		t.Errorf("Owner(%d) = %s, want solo", NumSlots-1, owner.ID)
+	}
+}
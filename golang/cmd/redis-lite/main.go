@@ -2,27 +2,58 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strings"
 
+	"github.com/afoley587/redis-lite/cluster"
 	"github.com/afoley587/redis-lite/persistence"
 	"github.com/afoley587/redis-lite/server"
 )
 
 var addrFlag string
 var aofPathFlag string
+var snapshotPathFlag string
+var clusterPeersFlag string
 
 func main() {
 	flag.StringVar(&addrFlag, "address", ":6379", "Address to bing to.")
 	flag.StringVar(&aofPathFlag, "aofPath", "/tmp/data", "Path on disk to create or read an AOF file.")
+	flag.StringVar(&snapshotPathFlag, "snapshotPath", "/tmp/data.rdb", "Path on disk to create or read a binary snapshot file.")
+	flag.StringVar(&clusterPeersFlag, "cluster-peers", "", "Comma-separated addresses of the other nodes in the cluster. If set, enables cluster mode.")
 	flag.Parse()
 
-	aof, err := persistence.NewAof(aofPathFlag)
+	snap := persistence.NewSnapshot(snapshotPathFlag)
+
+	startOffset, err := loadSnapshotIfFresh(snap, snapshotPathFlag, aofPathFlag)
+	if err != nil {
+		log.Fatalf("Failed to load snapshot: %v", err)
+	}
+
+	var aof *persistence.Aof
+	if startOffset > 0 {
+		aof, err = persistence.NewAofFromOffset(aofPathFlag, startOffset)
+	} else {
+		aof, err = persistence.NewAof(aofPathFlag)
+	}
 
 	if err != nil {
 		log.Fatalf("Failed to initialize AOF: %v", err)
 	}
 
-	s := server.NewServer(addrFlag, "tcp")
+	snap.RegisterHandlers(aof)
+
+	var clus *cluster.Cluster
+	if clusterPeersFlag != "" {
+		clus, err = cluster.New(addrFlag, strings.Split(clusterPeersFlag, ","))
+		if err != nil {
+			log.Fatalf("Failed to initialize cluster: %v", err)
+		}
+		clus.RegisterHandlers()
+	}
+
+	s := server.NewServer(addrFlag, "tcp", clus)
 	err = s.ListenAndServe(aof)
 
 	if err != nil {
@@ -30,3 +61,34 @@ func main() {
 	}
 
 }
+
+// loadSnapshotIfFresh loads the snapshot at snapPath, unless it doesn't
+// exist or the AOF at aofPath has been modified more recently -- in which
+// case a full AOF replay from offset 0 already covers everything the
+// snapshot would have. Returns the AOF offset the snapshot's data already
+// covers, or 0 if nothing was loaded.
+//
+// A snapshot that exists but fails to load (truncated, corrupt checksum)
+// isn't fatal: the AOF alone is still sufficient to reconstruct the
+// keyspace, so this falls back to a full AOF replay with just a logged
+// warning instead of refusing to start.
+func loadSnapshotIfFresh(snap *persistence.Snapshot, snapPath, aofPath string) (int64, error) {
+	snapInfo, err := os.Stat(snapPath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not stat snapshot file: %w", err)
+	}
+
+	if aofInfo, err := os.Stat(aofPath); err == nil && aofInfo.ModTime().After(snapInfo.ModTime()) {
+		return 0, nil
+	}
+
+	offset, err := snap.Load()
+	if err != nil {
+		log.Printf("Snapshot load failed, falling back to full AOF replay: %v", err)
+		return 0, nil
+	}
+	return offset, nil
+}
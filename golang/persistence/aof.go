@@ -14,14 +14,46 @@ import (
 	"github.com/afoley587/redis-lite/store"
 )
 
+// Defaults for the auto-aof-rewrite thresholds: a rewrite is triggered once
+// the file has grown to rewriteGrowthFactor times its size as of the last
+// rewrite, with rewriteMinSize as a floor so a tiny AOF doesn't get rewritten
+// on every other write.
+const (
+	defaultRewriteGrowthFactor = 2.0
+	defaultRewriteMinSize      = 64 * 1024 * 1024 // 64 MiB
+)
+
 type Aof struct {
+	path   string
 	file   *os.File
 	rd     *bufio.Reader
 	mu     sync.Mutex
 	syncPd time.Duration
+
+	// tail buffers writes that arrive while a rewrite is building its
+	// replacement file, so nothing issued mid-rewrite is lost. Non-nil only
+	// while a rewrite is in flight. Guarded by mu.
+	tail *[]byte
+
+	rewriting           bool
+	lastRewriteSize     int64
+	rewriteGrowthFactor float64
+	rewriteMinSize      int64
 }
 
 func NewAof(path string) (*Aof, error) {
+	return newAof(path, 0)
+}
+
+// NewAofFromOffset opens the AOF at path but skips replaying the prefix
+// before offset. It's used on startup once a snapshot has already restored
+// the keyspace as of offset, so only the suffix written since the snapshot
+// needs replaying.
+func NewAofFromOffset(path string, offset int64) (*Aof, error) {
+	return newAof(path, offset)
+}
+
+func newAof(path string, offset int64) (*Aof, error) {
 
 	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0666)
 
@@ -29,12 +61,31 @@ func NewAof(path string) (*Aof, error) {
 		return nil, fmt.Errorf("could not open AOF File: %w", err)
 	}
 
-	a := &Aof{file: file, rd: bufio.NewReader(file), syncPd: time.Second}
+	if offset > 0 {
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("could not seek AOF to snapshot offset: %w", err)
+		}
+	}
+
+	a := &Aof{
+		path:                path,
+		file:                file,
+		rd:                  bufio.NewReader(file),
+		syncPd:              time.Second,
+		rewriteGrowthFactor: defaultRewriteGrowthFactor,
+		rewriteMinSize:      defaultRewriteMinSize,
+	}
 
 	if err := a.Read(); err != nil {
 		return nil, fmt.Errorf("failed to restore AOF: %w", err)
 	}
 
+	if info, err := file.Stat(); err == nil {
+		a.lastRewriteSize = info.Size()
+	}
+
+	a.registerHandlers()
+
 	go a.sync()
 
 	return a, nil
@@ -53,6 +104,20 @@ func (a *Aof) sync() {
 	}
 }
 
+// Size returns the AOF file's current size on disk. SAVE/BGSAVE use it to
+// record how much of the log a snapshot already covers, so a later restart
+// only has to replay the suffix written after it.
+func (a *Aof) Size() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	info, err := a.file.Stat()
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
 func (a *Aof) Close() error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -67,45 +132,197 @@ func (a *Aof) Read() error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	data, err := io.ReadAll(a.rd)
-	if err != nil {
-		return fmt.Errorf("failed to read AOF: %w", err)
-	}
-
-	parser := resp.NewResp(data)
+	parser := resp.NewResp(a.rd)
+	discard := resp.NewConn(io.Discard) // replay doesn't need to write replies anywhere
 
-	for parser.HasNext() {
-		cmd, err := parser.Read()
+	for {
+		cmd, err := parser.ReadCommand()
 		if err != nil {
+			if err == io.EOF {
+				break
+			}
 			return fmt.Errorf("error parsing AOF command: %w", err)
 		}
 
-		if cmd.Type != resp.RespArray || len(cmd.Array) == 0 {
+		if len(cmd.Args) == 0 {
 			continue // ignore malformed input
 		}
 
-		commandName := strings.ToUpper(cmd.Array[0].Bulk)
-		args := cmd.Array[1:]
+		commandName := strings.ToUpper(string(cmd.Args[0]))
 
 		handler, ok := store.Handlers[commandName]
 		if !ok {
-			return fmt.Errorf("unknown command in AOF: %w", err)
+			return fmt.Errorf("unknown command in AOF: %s", commandName)
 		}
 
-		handler(args)
+		handler(discard, cmd)
 	}
 
 	return nil
 }
 
-func (a *Aof) Write(val resp.RespValue) error {
+func (a *Aof) Write(cmd resp.Command) error {
+	a.mu.Lock()
+
+	if _, err := a.file.Write(cmd.Raw); err != nil {
+		a.mu.Unlock()
+		return fmt.Errorf("could not save value to AOF: %w", err)
+	}
+
+	if a.tail != nil {
+		*a.tail = append(*a.tail, cmd.Raw...)
+	}
+
+	info, statErr := a.file.Stat()
+	a.mu.Unlock()
+
+	if statErr == nil && a.shouldRewrite(info.Size()) {
+		a.tryStartRewrite("auto")
+	}
+
+	return nil
+}
+
+// shouldRewrite reports whether size has grown past the auto-aof-rewrite
+// threshold: rewriteGrowthFactor times the size as of the last rewrite,
+// floored at rewriteMinSize.
+func (a *Aof) shouldRewrite(size int64) bool {
+	threshold := int64(float64(a.lastRewriteSize) * a.rewriteGrowthFactor)
+	if threshold < a.rewriteMinSize {
+		threshold = a.rewriteMinSize
+	}
+	return size >= threshold
+}
+
+// tryStartRewrite kicks off a background Rewrite unless one is already in
+// flight. It returns whether a rewrite was actually started.
+func (a *Aof) tryStartRewrite(reason string) bool {
+	a.mu.Lock()
+	if a.rewriting {
+		a.mu.Unlock()
+		return false
+	}
+	a.rewriting = true
+	a.mu.Unlock()
+
+	go func() {
+		if err := a.Rewrite(); err != nil {
+			log.Printf("AOF rewrite (%s) failed: %v", reason, err)
+		}
+		a.mu.Lock()
+		a.rewriting = false
+		a.mu.Unlock()
+	}()
+
+	return true
+}
+
+// Rewrite compacts the AOF by replacing it with the minimal command stream
+// needed to reconstruct the current keyspace, instead of every write ever
+// issued. The new file is built from a snapshot of the keyspace without
+// holding the write mutex; writes that arrive in the meantime are appended
+// to the live file as usual and also buffered into an in-memory tail, which
+// is copied onto the new file immediately before the atomic rename.
+//
+// This only avoids losing a command in-flight when Rewrite starts because
+// the caller (server.handleOneCommand) applies a command to the keyspace
+// before logging it to the AOF: arming the tail under the same mutex
+// Aof.Write checks it under means any command Write() still finds "not yet
+// tailed" (so it's only in the old, soon-to-be-discarded file) must have
+// already applied its effect to the keyspace -- so it's captured by
+// store.Snapshot() below instead. Reversing that order in the caller (AOF
+// write before the command is applied) would reopen a window where a
+// command is in neither the snapshot nor the tail.
+func (a *Aof) Rewrite() error {
+	tmpPath := a.path + ".rewrite"
+
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return fmt.Errorf("could not create AOF rewrite file: %w", err)
+	}
+
+	a.mu.Lock()
+	tail := make([]byte, 0)
+	a.tail = &tail
+	a.mu.Unlock()
+
+	for key, val := range store.Snapshot() {
+		if _, err := tmp.Write(commandForEntry(key, val).Marshal()); err != nil {
+			a.mu.Lock()
+			a.tail = nil
+			a.mu.Unlock()
+			tmp.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("could not write rewritten AOF entry: %w", err)
+		}
+	}
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	_, err := a.file.Write(val.Marshal())
+	if _, err := tmp.Write(tail); err != nil {
+		a.tail = nil
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not append AOF rewrite tail: %w", err)
+	}
+	a.tail = nil
 
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not fsync rewritten AOF: %w", err)
+	}
+
+	info, err := tmp.Stat()
 	if err != nil {
-		return fmt.Errorf("could not save value to AOF: %w", err)
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not stat rewritten AOF: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not close rewritten AOF: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, a.path); err != nil {
+		return fmt.Errorf("could not install rewritten AOF: %w", err)
+	}
+
+	if err := a.file.Close(); err != nil {
+		return fmt.Errorf("could not close previous AOF file: %w", err)
+	}
+
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf("could not reopen AOF after rewrite: %w", err)
 	}
+
+	a.file = file
+	a.lastRewriteSize = info.Size()
+
 	return nil
 }
+
+// commandForEntry renders a single cache entry as the minimal command that
+// reconstructs it. Only strings exist today; list/hash/set/zset types will
+// need RPUSH/HSET/SADD/ZADD encoders here once they land.
+func commandForEntry(key string, val resp.RespValue) resp.RespValue {
+	return resp.NewArray([]resp.RespValue{
+		resp.NewBulkString("SET"),
+		resp.NewBulkString(key),
+		resp.NewBulkString(val.Bulk),
+	})
+}
+
+// registerHandlers wires AOF-specific commands into the shared command
+// table. Called once from NewAof since these handlers close over this *Aof.
+func (a *Aof) registerHandlers() {
+	store.Handlers["BGREWRITEAOF"] = a.bgRewriteAOF
+}
+
+func (a *Aof) bgRewriteAOF(conn resp.Conn, cmd resp.Command) {
+	a.tryStartRewrite("BGREWRITEAOF")
+	conn.WriteString("Background append only file rewriting started")
+}
@@ -0,0 +1,309 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc64"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/afoley587/redis-lite/resp"
+	"github.com/afoley587/redis-lite/store"
+)
+
+// Snapshot format:
+//
+//	magic      [4]byte  "REDL"
+//	version    uint16
+//	aofOffset  uint64   // AOF byte offset already covered by this snapshot
+//	records... see recordType
+//	terminator uint8    recordEOF
+//	crc        uint64   // crc64(ISO) of every byte from aofOffset's end
+//	                     // through and including the terminator
+//
+// Each record is `type uint8 | keyLen uint32 | key | payload`. Only strings
+// exist today (payload is `uint32 len | bytes`); list/hash/set/zset will
+// each need their own record type and payload encoding once those data
+// types land, same as commandForEntry in aof.go.
+const (
+	snapshotMagic   = "REDL"
+	snapshotVersion = uint16(1)
+
+	recordTypeString = uint8(1)
+	recordEOF        = uint8(0xFF)
+)
+
+var snapshotCRCTable = crc64.MakeTable(crc64.ISO)
+
+// Snapshot manages a binary point-in-time dump of the keyspace, saved
+// alongside the AOF so startup can restore in O(dataset) instead of
+// replaying every write ever issued.
+type Snapshot struct {
+	path string
+
+	mu       sync.Mutex
+	saving   bool
+	lastSave time.Time
+
+	// saveMu serializes the build-temp-file+rename sequence across Save and
+	// BGSave so two overlapping saves can't write the same temp file at once.
+	saveMu sync.Mutex
+}
+
+// NewSnapshot returns a Snapshot backed by the file at path. It does not
+// touch disk until Save/BGSave/Load is called.
+func NewSnapshot(path string) *Snapshot {
+	return &Snapshot{path: path}
+}
+
+// Save synchronously writes the current keyspace to disk, recording
+// aofOffset as the point in the AOF this snapshot already covers.
+//
+// The caller reads aofOffset before calling Save, so it is always taken
+// before Save's copy of the keyspace. server.handleOneCommand applies a
+// command before logging it to the AOF, so every command counted in
+// aofOffset has already taken effect and is in the dump. A command that
+// lands between the two reads can be in both the dump and the AOF suffix
+// after aofOffset, and is then applied a second time when that suffix is
+// replayed.
+func (s *Snapshot) Save(aofOffset int64) error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	tmpPath := s.path + ".tmp"
+
+	var payload bytes.Buffer
+	for key, val := range store.Snapshot() {
+		if val.Type != resp.RespBulk {
+			continue // only strings are snapshotted today
+		}
+		writeStringRecord(&payload, key, val.Bulk)
+	}
+	payload.WriteByte(recordEOF)
+
+	crc := crc64.Checksum(payload.Bytes(), snapshotCRCTable)
+
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return fmt.Errorf("could not create snapshot file: %w", err)
+	}
+
+	if _, err := f.WriteString(snapshotMagic); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not write snapshot header: %w", err)
+	}
+	if err := binary.Write(f, binary.BigEndian, snapshotVersion); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not write snapshot version: %w", err)
+	}
+	if err := binary.Write(f, binary.BigEndian, uint64(aofOffset)); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not write snapshot AOF offset: %w", err)
+	}
+	if _, err := f.Write(payload.Bytes()); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not write snapshot records: %w", err)
+	}
+	if err := binary.Write(f, binary.BigEndian, crc); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not write snapshot checksum: %w", err)
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not fsync snapshot: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not close snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("could not install snapshot: %w", err)
+	}
+
+	s.mu.Lock()
+	s.lastSave = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+// BGSave runs Save in a goroutine, mirroring how BGREWRITEAOF backgrounds
+// Aof.Rewrite. It's a no-op if a save is already in flight.
+func (s *Snapshot) BGSave(aofOffset int64) bool {
+	s.mu.Lock()
+	if s.saving {
+		s.mu.Unlock()
+		return false
+	}
+	s.saving = true
+	s.mu.Unlock()
+
+	go func() {
+		if err := s.Save(aofOffset); err != nil {
+			log.Printf("BGSAVE failed: %v", err)
+		}
+		s.mu.Lock()
+		s.saving = false
+		s.mu.Unlock()
+	}()
+
+	return true
+}
+
+// LastSave returns the time of the last successful Save/BGSave or Load, or
+// the zero time if none has completed yet.
+func (s *Snapshot) LastSave() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSave
+}
+
+// Load restores the keyspace from the snapshot file at s.path and returns
+// the AOF offset recorded at save time, so the caller knows how much of the
+// AOF is already covered and can skip straight to replaying the suffix.
+func (s *Snapshot) Load() (aofOffset int64, err error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return 0, fmt.Errorf("could not open snapshot file: %w", err)
+	}
+	defer f.Close()
+
+	var magic [4]byte
+	if _, err := io.ReadFull(f, magic[:]); err != nil {
+		return 0, fmt.Errorf("could not read snapshot magic: %w", err)
+	}
+	if string(magic[:]) != snapshotMagic {
+		return 0, fmt.Errorf("not a snapshot file: bad magic %q", magic)
+	}
+
+	var version uint16
+	if err := binary.Read(f, binary.BigEndian, &version); err != nil {
+		return 0, fmt.Errorf("could not read snapshot version: %w", err)
+	}
+	if version != snapshotVersion {
+		return 0, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+
+	var offset uint64
+	if err := binary.Read(f, binary.BigEndian, &offset); err != nil {
+		return 0, fmt.Errorf("could not read snapshot AOF offset: %w", err)
+	}
+
+	rest, err := io.ReadAll(f)
+	if err != nil {
+		return 0, fmt.Errorf("could not read snapshot body: %w", err)
+	}
+	if len(rest) < 8 {
+		return 0, fmt.Errorf("truncated snapshot: missing checksum")
+	}
+
+	payload, wantCRC := rest[:len(rest)-8], binary.BigEndian.Uint64(rest[len(rest)-8:])
+	if gotCRC := crc64.Checksum(payload, snapshotCRCTable); gotCRC != wantCRC {
+		return 0, fmt.Errorf("snapshot checksum mismatch: got %x want %x", gotCRC, wantCRC)
+	}
+
+	if err := replaySnapshotPayload(payload); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	s.lastSave = time.Now()
+	s.mu.Unlock()
+
+	return int64(offset), nil
+}
+
+// RegisterHandlers wires SAVE/BGSAVE/LASTSAVE into the shared command table.
+// aof supplies the current AOF offset each snapshot should record, the same
+// way persistence.Aof registers its own BGREWRITEAOF handler.
+func (s *Snapshot) RegisterHandlers(aof *Aof) {
+	store.Handlers["SAVE"] = func(conn resp.Conn, cmd resp.Command) {
+		if err := s.Save(aof.Size()); err != nil {
+			conn.WriteError(fmt.Sprintf("ERR %v", err))
+			return
+		}
+		conn.WriteString("OK")
+	}
+
+	store.Handlers["BGSAVE"] = func(conn resp.Conn, cmd resp.Command) {
+		s.BGSave(aof.Size())
+		conn.WriteString("Background saving started")
+	}
+
+	store.Handlers["LASTSAVE"] = func(conn resp.Conn, cmd resp.Command) {
+		conn.WriteInt(int(s.LastSave().Unix()))
+	}
+}
+
+func writeStringRecord(buf *bytes.Buffer, key string, value string) {
+	buf.WriteByte(recordTypeString)
+	writeLenPrefixed(buf, []byte(key))
+	writeLenPrefixed(buf, []byte(value))
+}
+
+func writeLenPrefixed(buf *bytes.Buffer, data []byte) {
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(data)))
+	buf.Write(lenBuf[:])
+	buf.Write(data)
+}
+
+// replaySnapshotPayload walks the record stream, restoring each entry via
+// the shared SET handler the same way Aof.Read replays commands.
+func replaySnapshotPayload(payload []byte) error {
+	r := bytes.NewReader(payload)
+	discard := resp.NewConn(io.Discard)
+
+	for {
+		recordType, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("truncated snapshot: missing terminator: %w", err)
+		}
+		if recordType == recordEOF {
+			return nil
+		}
+		if recordType != recordTypeString {
+			return fmt.Errorf("unknown snapshot record type %d", recordType)
+		}
+
+		key, err := readLenPrefixed(r)
+		if err != nil {
+			return err
+		}
+		value, err := readLenPrefixed(r)
+		if err != nil {
+			return err
+		}
+
+		cmd := resp.Command{Args: [][]byte{[]byte("SET"), key, value}}
+		store.Handlers["SET"](discard, cmd)
+	}
+}
+
+func readLenPrefixed(r *bytes.Reader) ([]byte, error) {
+	var n uint32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, fmt.Errorf("truncated snapshot: missing length: %w", err)
+	}
+	if int(n) > r.Len() {
+		return nil, fmt.Errorf("truncated snapshot: length %d exceeds remaining %d bytes", n, r.Len())
+	}
+
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, fmt.Errorf("truncated snapshot: missing payload: %w", err)
+	}
+	return buf, nil
+}
@@ -0,0 +1,69 @@
+package persistence
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/afoley587/redis-lite/resp"
+	"github.com/afoley587/redis-lite/store"
+)
+
+func TestSnapshotSaveLoadRoundTrip(t *testing.T) {
+	discard := resp.NewConn(io.Discard)
+	store.Handlers["SET"](discard, resp.Command{Args: [][]byte{[]byte("SET"), []byte("snaptest:a"), []byte("1")}})
+	store.Handlers["SET"](discard, resp.Command{Args: [][]byte{[]byte("SET"), []byte("snaptest:b"), []byte("hello world")}})
+
+	snap := NewSnapshot(filepath.Join(t.TempDir(), "dump.rdb"))
+
+	const wantOffset = int64(42)
+	if err := snap.Save(wantOffset); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Overwrite one key and remove the other so Load has to actually restore
+	// state rather than the test passing by coincidence.
+	store.Handlers["SET"](discard, resp.Command{Args: [][]byte{[]byte("SET"), []byte("snaptest:a"), []byte("clobbered")}})
+	store.Handlers["DEL"](discard, resp.Command{Args: [][]byte{[]byte("DEL"), []byte("snaptest:b")}})
+
+	gotOffset, err := snap.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if gotOffset != wantOffset {
+		t.Errorf("Load returned aofOffset %d, want %d", gotOffset, wantOffset)
+	}
+
+	got := store.Snapshot()
+	if v := got["snaptest:a"]; v.Bulk != "1" {
+		t.Errorf("snaptest:a = %q after Load, want %q", v.Bulk, "1")
+	}
+	if v := got["snaptest:b"]; v.Bulk != "hello world" {
+		t.Errorf("snaptest:b = %q after Load, want %q", v.Bulk, "hello world")
+	}
+}
+
+func TestSnapshotLoadRejectsCorruptChecksum(t *testing.T) {
+	discard := resp.NewConn(io.Discard)
+	store.Handlers["SET"](discard, resp.Command{Args: [][]byte{[]byte("SET"), []byte("snaptest:c"), []byte("v")}})
+
+	path := filepath.Join(t.TempDir(), "dump.rdb")
+	snap := NewSnapshot(path)
+	if err := snap.Save(0); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	corrupt, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	corrupt[len(corrupt)-1] ^= 0xFF // flip a byte inside the trailing checksum
+	if err := os.WriteFile(path, corrupt, 0666); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if _, err := snap.Load(); err == nil {
+		t.Fatal("Load succeeded on a snapshot with a corrupted checksum, want error")
+	}
+}
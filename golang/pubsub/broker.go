@@ -0,0 +1,171 @@
+package pubsub
+
+import (
+	"sync"
+
+	"github.com/afoley587/redis-lite/resp"
+)
+
+// Broker tracks channel and pattern subscriptions and fans PUBLISH traffic
+// out to them. One Broker is shared by the whole server.
+type Broker struct {
+	mu       sync.RWMutex
+	channels map[string]map[resp.Conn]struct{}
+	patterns map[string]map[resp.Conn]struct{}
+}
+
+// NewBroker returns an empty Broker ready to accept subscriptions.
+func NewBroker() *Broker {
+	return &Broker{
+		channels: make(map[string]map[resp.Conn]struct{}),
+		patterns: make(map[string]map[resp.Conn]struct{}),
+	}
+}
+
+// Subscribe adds conn as a listener on each of channels.
+func (b *Broker) Subscribe(conn resp.Conn, channels ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range channels {
+		subs, ok := b.channels[ch]
+		if !ok {
+			subs = make(map[resp.Conn]struct{})
+			b.channels[ch] = subs
+		}
+		subs[conn] = struct{}{}
+	}
+}
+
+// PSubscribe adds conn as a listener on each of patterns.
+func (b *Broker) PSubscribe(conn resp.Conn, patterns ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, pat := range patterns {
+		subs, ok := b.patterns[pat]
+		if !ok {
+			subs = make(map[resp.Conn]struct{})
+			b.patterns[pat] = subs
+		}
+		subs[conn] = struct{}{}
+	}
+}
+
+// Unsubscribe removes conn from each of channels.
+func (b *Broker) Unsubscribe(conn resp.Conn, channels ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range channels {
+		subs, ok := b.channels[ch]
+		if !ok {
+			continue
+		}
+		delete(subs, conn)
+		if len(subs) == 0 {
+			delete(b.channels, ch)
+		}
+	}
+}
+
+// PUnsubscribe removes conn from each of patterns.
+func (b *Broker) PUnsubscribe(conn resp.Conn, patterns ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, pat := range patterns {
+		subs, ok := b.patterns[pat]
+		if !ok {
+			continue
+		}
+		delete(subs, conn)
+		if len(subs) == 0 {
+			delete(b.patterns, pat)
+		}
+	}
+}
+
+// Publish pushes message to every direct subscriber of channel plus every
+// connection subscribed to a pattern that matches it, and returns the
+// number of connections that received it.
+func (b *Broker) Publish(channel, message string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	receivers := 0
+	for conn := range b.channels[channel] {
+		pushMessage(conn, channel, message)
+		receivers++
+	}
+
+	for pattern, subs := range b.patterns {
+		if !MatchPattern(pattern, channel) {
+			continue
+		}
+		for conn := range subs {
+			pushPMessage(conn, pattern, channel, message)
+			receivers++
+		}
+	}
+
+	return receivers
+}
+
+// Channels returns the names of channels with at least one subscriber,
+// optionally filtered to those matching pattern (an empty pattern matches
+// everything).
+func (b *Broker) Channels(pattern string) []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	names := make([]string, 0, len(b.channels))
+	for ch := range b.channels {
+		if pattern == "" || MatchPattern(pattern, ch) {
+			names = append(names, ch)
+		}
+	}
+	return names
+}
+
+// NumSub returns the subscriber count for each of channels.
+func (b *Broker) NumSub(channels []string) map[string]int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	counts := make(map[string]int, len(channels))
+	for _, ch := range channels {
+		counts[ch] = len(b.channels[ch])
+	}
+	return counts
+}
+
+// NumPat returns the number of distinct patterns with at least one
+// subscriber.
+func (b *Broker) NumPat() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.patterns)
+}
+
+// pushMessage and pushPMessage each write a multi-part reply, so they build
+// it on a ReplyBuilder and flush it in one call rather than writing straight
+// to conn -- otherwise two concurrent PUBLISHes landing on the same
+// subscriber (or a push racing the subscriber's own reply, including a
+// connection PUBLISHing to a channel it's itself subscribed to) could
+// interleave their frames or deadlock on conn's writer lock.
+
+func pushMessage(conn resp.Conn, channel, message string) {
+	b := resp.NewReplyBuilder(conn.Proto())
+	b.WriteArray(3)
+	b.WriteBulk([]byte("message"))
+	b.WriteBulk([]byte(channel))
+	b.WriteBulk([]byte(message))
+	b.Flush(conn)
+}
+
+func pushPMessage(conn resp.Conn, pattern, channel, message string) {
+	b := resp.NewReplyBuilder(conn.Proto())
+	b.WriteArray(4)
+	b.WriteBulk([]byte("pmessage"))
+	b.WriteBulk([]byte(pattern))
+	b.WriteBulk([]byte(channel))
+	b.WriteBulk([]byte(message))
+	b.Flush(conn)
+}
@@ -0,0 +1,105 @@
+package pubsub
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/afoley587/redis-lite/resp"
+)
+
+// TestPublishToSelfDoesNotDeadlock models a connection publishing to a
+// channel it is itself subscribed to by calling Broker.Publish directly for
+// that subscriber: the push must be delivered instead of hanging on the
+// connection's writer lock. It does not go through the server's dispatch
+// loop.
+func TestPublishToSelfDoesNotDeadlock(t *testing.T) {
+	conn := resp.NewConn(&bytes.Buffer{})
+	b := NewBroker()
+	b.Subscribe(conn, "foo")
+
+	done := make(chan struct{})
+	go func() {
+		b.Publish("foo", "hi")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("publishing to a channel the publisher is itself subscribed to deadlocked")
+	}
+}
+
+// TestCrossConnectionPublishDoesNotDeadlock covers the circular-wait variant:
+// two connections each subscribed to the channel the other publishes to, with
+// both Broker.Publish calls running concurrently, must not deadlock.
+func TestCrossConnectionPublishDoesNotDeadlock(t *testing.T) {
+	connA := resp.NewConn(&bytes.Buffer{})
+	connB := resp.NewConn(&bytes.Buffer{})
+
+	b := NewBroker()
+	b.Subscribe(connA, "b-events")
+	b.Subscribe(connB, "a-events")
+
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() { defer wg.Done(); b.Publish("a-events", "from-a") }()
+		go func() { defer wg.Done(); b.Publish("b-events", "from-b") }()
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("concurrent publishes between mutually subscribed connections deadlocked")
+	}
+}
+
+// TestPublishConcurrentIsNotInterleaved checks the flip side of removing the
+// dispatch loop's blanket lock: concurrent pushes to the same subscriber
+// must still land as whole, unmangled frames rather than interleaving their
+// bytes on the wire.
+func TestPublishConcurrentIsNotInterleaved(t *testing.T) {
+	var out bytes.Buffer
+	conn := resp.NewConn(&out)
+
+	b := NewBroker()
+	b.Subscribe(conn, "events")
+
+	const n = 50
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			b.Publish("events", fmt.Sprintf("msg-%d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	r := resp.NewResp(bytes.NewReader(out.Bytes()))
+	count := 0
+	for {
+		val, err := r.ReadReply()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("push %d: corrupted RESP stream: %v", count, err)
+		}
+		if val.Type != resp.RespArray || len(val.Array) != 3 {
+			t.Fatalf("push %d: got %+v, want a 3-element message array", count, val)
+		}
+		count++
+	}
+	if count != n {
+		t.Fatalf("got %d pushes, want %d", count, n)
+	}
+}
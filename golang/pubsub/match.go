@@ -0,0 +1,106 @@
+package pubsub
+
+// MatchPattern reports whether s matches pattern using Redis's
+// stringmatchlen glob semantics: '*' matches any run of characters
+// (including none), '?' matches exactly one, '[...]' matches a character
+// class (supporting ranges like a-z and leading '^' negation), and '\'
+// escapes the next character so it's matched literally.
+func MatchPattern(pattern, s string) bool {
+	return matchHere([]byte(pattern), []byte(s))
+}
+
+func matchHere(pattern, s []byte) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchHere(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end, matched := matchClass(pattern, s[0])
+			if !matched {
+				return false
+			}
+			pattern, s = pattern[end:], s[1:]
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+// matchClass parses a `[...]` class starting at pattern[0] == '[' and
+// reports whether c falls inside it, along with the index just past the
+// closing ']' so the caller can resume matching there.
+func matchClass(pattern []byte, c byte) (end int, matched bool) {
+	i := 1
+	negate := false
+	if i < len(pattern) && pattern[i] == '^' {
+		negate = true
+		i++
+	}
+
+	found := false
+	for i < len(pattern) && pattern[i] != ']' {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			i++
+			if pattern[i] == c {
+				found = true
+			}
+			i++
+		case i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']':
+			lo, hi := pattern[i], pattern[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				found = true
+			}
+			i += 3
+		default:
+			if pattern[i] == c {
+				found = true
+			}
+			i++
+		}
+	}
+
+	if i < len(pattern) && pattern[i] == ']' {
+		i++
+	}
+
+	if negate {
+		found = !found
+	}
+
+	return i, found
+}
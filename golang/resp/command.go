@@ -0,0 +1,35 @@
+package resp
+
+import "fmt"
+
+// Command is a single parsed client command: the raw frame bytes alongside
+// the already-split argument list. Args[0] is the command name, matching
+// the wire's leading bulk string.
+type Command struct {
+	Raw  []byte
+	Args [][]byte
+}
+
+// ReadCommand reads the next top-level frame and decodes it as a client
+// command: a RESP array of bulk strings. Like ReadReply, it returns io.EOF
+// unwrapped at connection close.
+func (r *Resp) ReadCommand() (Command, error) {
+	val, err := r.ReadReply()
+	if err != nil {
+		return Command{}, err
+	}
+
+	if val.Type != RespArray {
+		return Command{}, fmt.Errorf("expected array frame for command, got %q", val.Type)
+	}
+
+	args := make([][]byte, 0, len(val.Array))
+	for _, v := range val.Array {
+		if v.Type != RespBulk {
+			return Command{}, fmt.Errorf("expected bulk string command argument, got %q", v.Type)
+		}
+		args = append(args, []byte(v.Bulk))
+	}
+
+	return Command{Raw: val.Marshal(), Args: args}, nil
+}
@@ -0,0 +1,167 @@
+package resp
+
+import (
+	"io"
+	"sync"
+)
+
+// RESP protocol versions a connection can negotiate via HELLO.
+const (
+	ProtoRESP2 = 2
+	ProtoRESP3 = 3
+)
+
+// Conn is the per-connection API handlers use to write replies. Modeled on
+// the redcon library, it lets a handler stream large multi-bulk replies
+// without materializing them first, and carry state across calls on the
+// same connection (needed for MULTI/EXEC and SUBSCRIBE).
+type Conn interface {
+	WriteString(s string)
+	WriteError(msg string)
+	WriteInt(i int)
+	WriteBulk(b []byte)
+	WriteNull()
+	// WriteArray declares the length of an array reply; the caller follows
+	// up with n further Write* calls for the elements.
+	WriteArray(n int)
+
+	// RESP3-only reply types. On a connection still speaking RESP2 these
+	// downgrade to their closest RESP2 equivalent instead of erroring, the
+	// same way real Redis does.
+	WriteMap(n int) // declares n key/value pairs; 2n further Write* calls follow
+	WriteSet(n int)
+	WriteDouble(f float64)
+	WriteBoolean(b bool)
+	WriteBigNumber(s string)
+	WriteVerbatimString(format, text string)
+	WriteBlobError(msg string)
+
+	// WriteRaw writes an already-marshaled reply in one call. Each Write*
+	// method above is independently safe against a concurrent writer on the
+	// same Conn (most commonly an async pub/sub push racing a handler's own
+	// reply); a reply built from more than one Write* call is not, since
+	// another goroutine's write can land between them and interleave frames.
+	// Callers assembling such a reply should build it with a ReplyBuilder
+	// and flush it through WriteRaw as a single call instead of calling
+	// Write* directly.
+	WriteRaw(b []byte)
+
+	// Proto and SetProto track the protocol version negotiated by HELLO.
+	// Connections start on RESP2 until a client asks for RESP3.
+	Proto() int
+	SetProto(version int)
+
+	// Context and SetContext let a handler stash per-connection state (e.g.
+	// MULTI/EXEC queues, subscription lists) between calls.
+	Context() interface{}
+	SetContext(ctx interface{})
+}
+
+// conn is the default Conn, writing RESP straight through to the
+// underlying connection. mu serializes individual writes so two goroutines
+// writing to the same Conn at once (a handler's reply and an async pub/sub
+// push, most commonly) can't tear a single Write*/WriteRaw call in half.
+type conn struct {
+	w     io.Writer
+	mu    sync.Mutex
+	ctx   interface{}
+	proto int
+}
+
+// NewConn wraps w (typically a net.Conn) as a Conn for handlers to write
+// replies through. New connections start on RESP2 until HELLO negotiates
+// RESP3.
+func NewConn(w io.Writer) Conn {
+	return &conn{w: w, proto: ProtoRESP2}
+}
+
+func (c *conn) Context() interface{}       { return c.ctx }
+func (c *conn) SetContext(ctx interface{}) { c.ctx = ctx }
+
+func (c *conn) Proto() int           { return c.proto }
+func (c *conn) SetProto(version int) { c.proto = version }
+
+func (c *conn) WriteString(s string) { c.write(marshalSimpleString(s)) }
+func (c *conn) WriteError(msg string) { c.write(marshalError(msg)) }
+func (c *conn) WriteInt(i int)        { c.write(marshalInteger(i)) }
+func (c *conn) WriteBulk(b []byte)    { c.write(marshalBulk(string(b))) }
+func (c *conn) WriteArray(n int)      { c.write(marshalArrayHeader(n)) }
+
+func (c *conn) WriteNull() {
+	if c.proto == ProtoRESP3 {
+		c.write(marshalNull())
+		return
+	}
+	c.write(marshalNullBulk())
+}
+
+func (c *conn) WriteMap(n int) {
+	if c.proto == ProtoRESP3 {
+		c.write(marshalMapHeader(n))
+		return
+	}
+	// RESP2 has no map type: downgrade to a flat array of the 2n elements.
+	c.write(marshalArrayHeader(n * 2))
+}
+
+func (c *conn) WriteSet(n int) {
+	if c.proto == ProtoRESP3 {
+		c.write(marshalSetHeader(n))
+		return
+	}
+	c.write(marshalArrayHeader(n))
+}
+
+func (c *conn) WriteDouble(f float64) {
+	if c.proto == ProtoRESP3 {
+		c.write(marshalDouble(f))
+		return
+	}
+	c.write(marshalBulk(formatRespDouble(f)))
+}
+
+func (c *conn) WriteBoolean(b bool) {
+	if c.proto == ProtoRESP3 {
+		c.write(marshalBoolean(b))
+		return
+	}
+	if b {
+		c.write(marshalInteger(1))
+		return
+	}
+	c.write(marshalInteger(0))
+}
+
+func (c *conn) WriteBigNumber(s string) {
+	if c.proto == ProtoRESP3 {
+		c.write(marshalBigNumber(s))
+		return
+	}
+	c.write(marshalBulk(s))
+}
+
+func (c *conn) WriteVerbatimString(format, text string) {
+	if c.proto == ProtoRESP3 {
+		c.write(marshalVerbatim(VerbatimString{Format: format, Text: text}))
+		return
+	}
+	c.write(marshalBulk(text))
+}
+
+func (c *conn) WriteBlobError(msg string) {
+	if c.proto == ProtoRESP3 {
+		c.write(marshalBlobError(msg))
+		return
+	}
+	c.write(marshalError(msg))
+}
+
+func (c *conn) WriteRaw(b []byte) { c.write(b) }
+
+// write locks around the underlying io.Writer so no two calls -- from any
+// goroutine -- interleave their bytes on the wire.
+func (c *conn) write(b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.w.Write(b)
+}
@@ -1,33 +1,56 @@
 package resp
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"strconv"
 )
 
+const (
+	// maxBulkLen mirrors real Redis's default proto-max-bulk-len: the
+	// largest body a bulk string, verbatim string, or blob error is allowed
+	// to declare. Without this, a client can send "$50000000000\r\n" and
+	// force an allocation attempt long before io.ReadFull ever fails, which
+	// recover() in the dispatch loop can't undo.
+	maxBulkLen = 512 * 1024 * 1024
+
+	// maxAggregateLen mirrors Redis's proto-max-multibulk-len: the largest
+	// element count an array/set/map header may declare, for the same
+	// reason maxBulkLen exists for bulk bodies.
+	maxAggregateLen = 1024 * 1024
+)
+
+// Resp is a streaming RESP parser. It wraps a bufio.Reader so frames can be
+// read one at a time as bytes arrive, rather than requiring the whole
+// command (or a pipeline of commands) to already be buffered in memory.
 type Resp struct {
-	Values []RespValue // Parsed top-level values
-	curr   int
-	buf    []byte
+	reader *bufio.Reader
 }
 
-func NewResp(buf []byte) *Resp {
-	return &Resp{
-		buf:    buf,
-		Values: make([]RespValue, 0),
-	}
+// NewResp wraps rd in a buffered reader ready for streaming RESP frames.
+// Callers reading from a net.Conn should construct one Resp per connection
+// and reuse it for the connection's lifetime so the underlying buffer is
+// reused across reads instead of reallocated.
+func NewResp(rd io.Reader) *Resp {
+	return &Resp{reader: bufio.NewReader(rd)}
 }
 
-func (r *Resp) Read() (RespValue, error) {
-	if r.curr >= len(r.buf) {
-		return RespValue{}, fmt.Errorf("empty buffer")
+// ReadReply blocks until a complete RESP frame is available and returns it.
+// It returns io.EOF unwrapped when the underlying reader is closed with no
+// partial frame pending, so callers can treat it the same way as a closed
+// net.Conn.
+func (r *Resp) ReadReply() (RespValue, error) {
+	b, err := r.reader.ReadByte()
+	if err != nil {
+		return RespValue{}, err
 	}
 
-	switch r.readByte() {
+	switch b {
 	case RespString:
-		return r.readSimpleString(), nil
+		return r.readSimpleString()
 	case RespError:
-		return r.readError(), nil
+		return r.readError()
 	case RespInteger:
 		return r.readInteger()
 	case RespBulk:
@@ -36,103 +59,321 @@ func (r *Resp) Read() (RespValue, error) {
 		return r.readArray()
 	case RespNull:
 		return NewNull(), nil
+	case RespMap:
+		return r.readMap()
+	case RespSet:
+		return r.readSet()
+	case RespDouble:
+		return r.readDouble()
+	case RespBoolean:
+		return r.readBoolean()
+	case RespBigNumber:
+		return r.readBigNumber()
+	case RespVerbatim:
+		return r.readVerbatimString()
+	case RespBlobError:
+		return r.readBlobError()
+	case RespStreamEnd:
+		return r.readStreamEnd()
 	default:
-		return RespValue{}, fmt.Errorf("unknown RESP type at byte: %d", r.curr-1)
+		return RespValue{}, fmt.Errorf("unknown RESP type: %q", b)
 	}
 }
 
-func (r *Resp) HasNext() bool {
-	// Skip trailing whitespace like \r or \n if any
-	for r.curr < len(r.buf) {
-		if r.buf[r.curr] != '\r' && r.buf[r.curr] != '\n' {
-			return true
-		}
-		r.curr++
+func (r *Resp) readSimpleString() (RespValue, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return RespValue{}, err
 	}
-	return false
+	return NewSimpleString(string(line)), nil
 }
 
-func (r *Resp) readSimpleString() RespValue {
-	str := r.readLine()
-	return NewSimpleString(string(str))
-}
-
-func (r *Resp) readError() RespValue {
-	msg := r.readLine()
-	return NewError(string(msg))
+func (r *Resp) readError() (RespValue, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return RespValue{}, err
+	}
+	return NewError(string(line)), nil
 }
 
 func (r *Resp) readInteger() (RespValue, error) {
-	line := r.readLine()
+	line, err := r.readLine()
+	if err != nil {
+		return RespValue{}, err
+	}
 	num, err := strconv.Atoi(string(line))
 	if err != nil {
-		return RespValue{}, fmt.Errorf("invalid integer: %v", err)
+		return RespValue{}, fmt.Errorf("invalid integer: %w", err)
 	}
 	return NewInteger(num), nil
 }
 
 func (r *Resp) readBulk() (RespValue, error) {
-	lengthLine := r.readLine()
+	lengthLine, err := r.readLine()
+	if err != nil {
+		return RespValue{}, err
+	}
+
 	length, err := strconv.Atoi(string(lengthLine))
 	if err != nil {
-		return RespValue{}, fmt.Errorf("invalid bulk string length: %v", err)
+		return RespValue{}, fmt.Errorf("invalid bulk string length: %w", err)
 	}
 
 	if length == -1 {
 		return NewNull(), nil
 	}
+	if length < -1 {
+		return RespValue{}, fmt.Errorf("invalid bulk string length: %d", length)
+	}
+	if length > maxBulkLen {
+		return RespValue{}, fmt.Errorf("bulk string length %d exceeds maximum of %d", length, maxBulkLen)
+	}
 
-	start := r.curr
-	end := start + length
-
-	if end > len(r.buf) {
-		return RespValue{}, fmt.Errorf("bulk string out of bounds")
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r.reader, buf); err != nil {
+		return RespValue{}, fmt.Errorf("bulk string body: %w", err)
 	}
 
-	bulk := string(r.buf[start:end])
-	r.curr = end + 2 // skip CRLF
+	if err := r.discardCRLF(); err != nil {
+		return RespValue{}, err
+	}
 
-	return NewBulkString(bulk), nil
+	return NewBulkString(string(buf)), nil
 }
 
 func (r *Resp) readArray() (RespValue, error) {
-	lengthLine := r.readLine()
-	length, err := strconv.Atoi(string(lengthLine))
+	length, streaming, err := r.readAggregateLength()
 	if err != nil {
-		return RespValue{}, fmt.Errorf("invalid array length: %v", err)
+		return RespValue{}, err
 	}
 
-	if length == -1 {
+	if !streaming && length == -1 {
 		return NewNull(), nil
 	}
 
-	values := make([]RespValue, 0, length)
-	for i := 0; i < length; i++ {
-		val, err := r.Read()
+	values, err := r.readAggregateElements(length, streaming)
+	if err != nil {
+		return RespValue{}, fmt.Errorf("array: %w", err)
+	}
+
+	return NewArray(values), nil
+}
+
+func (r *Resp) readSet() (RespValue, error) {
+	length, streaming, err := r.readAggregateLength()
+	if err != nil {
+		return RespValue{}, err
+	}
+
+	values, err := r.readAggregateElements(length, streaming)
+	if err != nil {
+		return RespValue{}, fmt.Errorf("set: %w", err)
+	}
+
+	return NewSet(values), nil
+}
+
+func (r *Resp) readMap() (RespValue, error) {
+	length, streaming, err := r.readAggregateLength()
+	if err != nil {
+		return RespValue{}, err
+	}
+
+	capHint := length
+	if capHint < 0 {
+		capHint = 0
+	}
+	entries := make([]MapEntry, 0, capHint)
+	for streaming || len(entries) < length {
+		key, err := r.ReadReply()
 		if err != nil {
-			return RespValue{}, fmt.Errorf("array item %d: %v", i, err)
+			return RespValue{}, fmt.Errorf("map: %w", err)
 		}
-		values = append(values, val)
+		if streaming && key.Type == RespStreamEnd {
+			break
+		}
+
+		value, err := r.ReadReply()
+		if err != nil {
+			return RespValue{}, fmt.Errorf("map: %w", err)
+		}
+		entries = append(entries, MapEntry{Key: key, Value: value})
 	}
 
-	return NewArray(values), nil
+	return NewMap(entries), nil
 }
 
-func (r *Resp) readLine() []byte {
-	start := r.curr
-	for {
-		if r.curr+1 >= len(r.buf) || (r.buf[r.curr] == '\r' && r.buf[r.curr+1] == '\n') {
+// readAggregateLength reads the `N\r\n` (or `?\r\n` for a RESP3 streaming
+// aggregate) that follows an array/set/map type byte.
+func (r *Resp) readAggregateLength() (length int, streaming bool, err error) {
+	line, err := r.readLine()
+	if err != nil {
+		return 0, false, err
+	}
+
+	if string(line) == "?" {
+		return 0, true, nil
+	}
+
+	length, err = strconv.Atoi(string(line))
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid aggregate length: %w", err)
+	}
+	if length < -1 {
+		return 0, false, fmt.Errorf("invalid aggregate length: %d", length)
+	}
+	if length > maxAggregateLen {
+		return 0, false, fmt.Errorf("aggregate length %d exceeds maximum of %d", length, maxAggregateLen)
+	}
+	return length, false, nil
+}
+
+// readAggregateElements reads either a fixed number of elements, or (when
+// streaming is true) elements up to a RespStreamEnd terminator.
+func (r *Resp) readAggregateElements(length int, streaming bool) ([]RespValue, error) {
+	capHint := length
+	if capHint < 0 {
+		capHint = 0
+	}
+	values := make([]RespValue, 0, capHint)
+	for streaming || len(values) < length {
+		val, err := r.ReadReply()
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", len(values), err)
+		}
+		if streaming && val.Type == RespStreamEnd {
 			break
 		}
-		r.curr++
+		values = append(values, val)
+	}
+	return values, nil
+}
+
+func (r *Resp) readDouble() (RespValue, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return RespValue{}, err
+	}
+	f, err := parseRespDouble(string(line))
+	if err != nil {
+		return RespValue{}, fmt.Errorf("invalid double: %w", err)
+	}
+	return NewDouble(f), nil
+}
+
+func (r *Resp) readBoolean() (RespValue, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return RespValue{}, err
+	}
+	switch string(line) {
+	case "t":
+		return NewBoolean(true), nil
+	case "f":
+		return NewBoolean(false), nil
+	default:
+		return RespValue{}, fmt.Errorf("invalid boolean: %q", line)
+	}
+}
+
+func (r *Resp) readBigNumber() (RespValue, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return RespValue{}, err
+	}
+	return NewBigNumber(string(line)), nil
+}
+
+func (r *Resp) readVerbatimString() (RespValue, error) {
+	lengthLine, err := r.readLine()
+	if err != nil {
+		return RespValue{}, err
+	}
+
+	length, err := strconv.Atoi(string(lengthLine))
+	if err != nil {
+		return RespValue{}, fmt.Errorf("invalid verbatim string length: %w", err)
+	}
+	if length < 0 {
+		return RespValue{}, fmt.Errorf("invalid verbatim string length: %d", length)
+	}
+	if length > maxBulkLen {
+		return RespValue{}, fmt.Errorf("verbatim string length %d exceeds maximum of %d", length, maxBulkLen)
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r.reader, buf); err != nil {
+		return RespValue{}, fmt.Errorf("verbatim string body: %w", err)
 	}
-	end := r.curr
-	r.curr += 2 // skip CRLF
-	return r.buf[start:end]
+	if err := r.discardCRLF(); err != nil {
+		return RespValue{}, err
+	}
+
+	if length < 4 || buf[3] != ':' {
+		return RespValue{}, fmt.Errorf("malformed verbatim string: missing format prefix")
+	}
+
+	return NewVerbatimString(string(buf[:3]), string(buf[4:])), nil
+}
+
+func (r *Resp) readBlobError() (RespValue, error) {
+	lengthLine, err := r.readLine()
+	if err != nil {
+		return RespValue{}, err
+	}
+
+	length, err := strconv.Atoi(string(lengthLine))
+	if err != nil {
+		return RespValue{}, fmt.Errorf("invalid blob error length: %w", err)
+	}
+	if length < 0 {
+		return RespValue{}, fmt.Errorf("invalid blob error length: %d", length)
+	}
+	if length > maxBulkLen {
+		return RespValue{}, fmt.Errorf("blob error length %d exceeds maximum of %d", length, maxBulkLen)
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r.reader, buf); err != nil {
+		return RespValue{}, fmt.Errorf("blob error body: %w", err)
+	}
+	if err := r.discardCRLF(); err != nil {
+		return RespValue{}, err
+	}
+
+	return NewBlobError(string(buf)), nil
+}
+
+// readStreamEnd consumes the empty line after a `.` terminator byte.
+func (r *Resp) readStreamEnd() (RespValue, error) {
+	if _, err := r.readLine(); err != nil {
+		return RespValue{}, err
+	}
+	return RespValue{Type: RespStreamEnd}, nil
 }
 
-func (r *Resp) readByte() byte {
-	b := r.buf[r.curr]
-	r.curr++
-	return b
+// readLine reads up to, and consumes, the trailing CRLF, returning the line
+// without it.
+func (r *Resp) readLine() ([]byte, error) {
+	line, err := r.reader.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("line missing CRLF terminator")
+	}
+
+	return line[:len(line)-2], nil
+}
+
+func (r *Resp) discardCRLF() error {
+	crlf := make([]byte, 2)
+	if _, err := io.ReadFull(r.reader, crlf); err != nil {
+		return fmt.Errorf("missing CRLF terminator: %w", err)
+	}
+	if crlf[0] != '\r' || crlf[1] != '\n' {
+		return fmt.Errorf("malformed CRLF terminator")
+	}
+	return nil
 }
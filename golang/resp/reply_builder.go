@@ -0,0 +1,49 @@
+package resp
+
+import "bytes"
+
+// ReplyBuilder assembles a reply that spans more than one RESP value (e.g.
+// WriteArray(n) followed by n element writes, or a pub/sub push) so it can
+// reach the wire through a single Conn.WriteRaw call. Building happens on a
+// private, unshared buffer with no locking of its own; only the final Flush
+// touches the Conn, so the whole reply lands atomically without holding any
+// lock across the calls that assemble it.
+type ReplyBuilder struct {
+	buf   bytes.Buffer
+	proto int
+}
+
+// NewReplyBuilder returns an empty ReplyBuilder. proto should be the
+// producing Conn's negotiated protocol version, so RESP3-only types
+// downgrade the same way Conn's own Write* methods do.
+func NewReplyBuilder(proto int) *ReplyBuilder {
+	return &ReplyBuilder{proto: proto}
+}
+
+func (b *ReplyBuilder) WriteString(s string) { b.buf.Write(marshalSimpleString(s)) }
+func (b *ReplyBuilder) WriteInt(i int)        { b.buf.Write(marshalInteger(i)) }
+func (b *ReplyBuilder) WriteBulk(v []byte)    { b.buf.Write(marshalBulk(string(v))) }
+func (b *ReplyBuilder) WriteArray(n int)      { b.buf.Write(marshalArrayHeader(n)) }
+
+func (b *ReplyBuilder) WriteNull() {
+	if b.proto == ProtoRESP3 {
+		b.buf.Write(marshalNull())
+		return
+	}
+	b.buf.Write(marshalNullBulk())
+}
+
+func (b *ReplyBuilder) WriteMap(n int) {
+	if b.proto == ProtoRESP3 {
+		b.buf.Write(marshalMapHeader(n))
+		return
+	}
+	b.buf.Write(marshalArrayHeader(n * 2))
+}
+
+// Flush writes the assembled reply to conn in one call and resets the
+// builder so it can be reused for another reply.
+func (b *ReplyBuilder) Flush(conn Conn) {
+	conn.WriteRaw(b.buf.Bytes())
+	b.buf.Reset()
+}
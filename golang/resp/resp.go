@@ -1,6 +1,9 @@
 package resp
 
-import "strconv"
+import (
+	"math"
+	"strconv"
+)
 
 const (
 	RespString  = '+'
@@ -8,15 +11,49 @@ const (
 	RespInteger = ':'
 	RespBulk    = '$'
 	RespArray   = '*'
-	RespNull    = '_'
+	RespNull    = '_' // RESP3 null; RESP2 connections downgrade this to the null bulk string $-1
+
+	// RESP3-only types.
+	RespMap       = '%'
+	RespSet       = '~'
+	RespDouble    = ','
+	RespBoolean   = '#'
+	RespBigNumber = '('
+	RespVerbatim  = '='
+	RespBlobError = '!'
+
+	// RespStreamEnd terminates a RESP3 streaming aggregate (`*?\r\n`,
+	// `%?\r\n`, `~?\r\n`) and never appears outside one.
+	RespStreamEnd = '.'
 )
 
+// MapEntry is one key/value pair of a RESP3 map reply.
+type MapEntry struct {
+	Key   RespValue
+	Value RespValue
+}
+
+// VerbatimString is a RESP3 verbatim string: a 3-byte format code (e.g.
+// "txt", "mkd") plus the text it describes.
+type VerbatimString struct {
+	Format string
+	Text   string
+}
+
 type RespValue struct {
 	Type    byte
 	String  string
 	Integer int
 	Bulk    string
 	Array   []RespValue
+
+	// RESP3 fields. Only the ones matching Type are populated.
+	Map       []MapEntry
+	Set       []RespValue
+	Double    float64
+	Boolean   bool
+	BigNumber string
+	Verbatim  VerbatimString
 }
 
 // Factory methods for constructing RESP values
@@ -44,6 +81,34 @@ func NewError(msg string) RespValue {
 	return RespValue{Type: RespError, String: msg}
 }
 
+func NewMap(entries []MapEntry) RespValue {
+	return RespValue{Type: RespMap, Map: entries}
+}
+
+func NewSet(vals []RespValue) RespValue {
+	return RespValue{Type: RespSet, Set: vals}
+}
+
+func NewDouble(f float64) RespValue {
+	return RespValue{Type: RespDouble, Double: f}
+}
+
+func NewBoolean(b bool) RespValue {
+	return RespValue{Type: RespBoolean, Boolean: b}
+}
+
+func NewBigNumber(s string) RespValue {
+	return RespValue{Type: RespBigNumber, BigNumber: s}
+}
+
+func NewVerbatimString(format, text string) RespValue {
+	return RespValue{Type: RespVerbatim, Verbatim: VerbatimString{Format: format, Text: text}}
+}
+
+func NewBlobError(msg string) RespValue {
+	return RespValue{Type: RespBlobError, String: msg}
+}
+
 func (rv RespValue) Marshal() []byte {
 	switch rv.Type {
 	case RespString:
@@ -58,6 +123,20 @@ func (rv RespValue) Marshal() []byte {
 		return marshalArray(rv.Array)
 	case RespNull:
 		return marshalNull()
+	case RespMap:
+		return marshalMap(rv.Map)
+	case RespSet:
+		return marshalSet(rv.Set)
+	case RespDouble:
+		return marshalDouble(rv.Double)
+	case RespBoolean:
+		return marshalBoolean(rv.Boolean)
+	case RespBigNumber:
+		return marshalBigNumber(rv.BigNumber)
+	case RespVerbatim:
+		return marshalVerbatim(rv.Verbatim)
+	case RespBlobError:
+		return marshalBlobError(rv.String)
 	default:
 		return []byte{}
 	}
@@ -85,16 +164,124 @@ func marshalBulk(b string) []byte {
 }
 
 func marshalArray(arr []RespValue) []byte {
-	out := []byte{RespArray}
-	out = append(out, []byte(strconv.Itoa(len(arr)))...)
-	out = append(out, '\r', '\n')
-
+	out := marshalArrayHeader(len(arr))
 	for _, v := range arr {
 		out = append(out, v.Marshal()...)
 	}
 	return out
 }
 
+// marshalArrayHeader encodes just the `*N\r\n` length prefix of an array,
+// letting callers (e.g. Conn.WriteArray) stream the elements themselves
+// instead of materializing the whole array up front.
+func marshalArrayHeader(n int) []byte {
+	out := []byte{RespArray}
+	out = append(out, []byte(strconv.Itoa(n))...)
+	out = append(out, '\r', '\n')
+	return out
+}
+
 func marshalNull() []byte {
 	return []byte{RespNull, '\r', '\n'}
 }
+
+// marshalNullBulk is the RESP2 null: a bulk string of length -1. RESP2 has
+// no dedicated null type, so this is what RespNull downgrades to on a
+// connection that hasn't negotiated RESP3 via HELLO.
+func marshalNullBulk() []byte {
+	return []byte{RespBulk, '-', '1', '\r', '\n'}
+}
+
+func marshalMapHeader(n int) []byte {
+	out := []byte{RespMap}
+	out = append(out, []byte(strconv.Itoa(n))...)
+	out = append(out, '\r', '\n')
+	return out
+}
+
+func marshalMap(entries []MapEntry) []byte {
+	out := marshalMapHeader(len(entries))
+	for _, e := range entries {
+		out = append(out, e.Key.Marshal()...)
+		out = append(out, e.Value.Marshal()...)
+	}
+	return out
+}
+
+func marshalSetHeader(n int) []byte {
+	out := []byte{RespSet}
+	out = append(out, []byte(strconv.Itoa(n))...)
+	out = append(out, '\r', '\n')
+	return out
+}
+
+func marshalSet(vals []RespValue) []byte {
+	out := marshalSetHeader(len(vals))
+	for _, v := range vals {
+		out = append(out, v.Marshal()...)
+	}
+	return out
+}
+
+func marshalDouble(f float64) []byte {
+	return append([]byte{RespDouble}, append([]byte(formatRespDouble(f)), '\r', '\n')...)
+}
+
+// formatRespDouble renders f the way RESP3 doubles are written on the wire,
+// using the protocol's special spellings for the non-finite values.
+func formatRespDouble(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// parseRespDouble is the inverse of formatRespDouble.
+func parseRespDouble(s string) (float64, error) {
+	switch s {
+	case "inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan":
+		return math.NaN(), nil
+	default:
+		return strconv.ParseFloat(s, 64)
+	}
+}
+
+func marshalBoolean(b bool) []byte {
+	if b {
+		return []byte{RespBoolean, 't', '\r', '\n'}
+	}
+	return []byte{RespBoolean, 'f', '\r', '\n'}
+}
+
+func marshalBigNumber(s string) []byte {
+	return append([]byte{RespBigNumber}, append([]byte(s), '\r', '\n')...)
+}
+
+func marshalVerbatim(v VerbatimString) []byte {
+	body := v.Format + ":" + v.Text
+	out := []byte{RespVerbatim}
+	out = append(out, []byte(strconv.Itoa(len(body)))...)
+	out = append(out, '\r', '\n')
+	out = append(out, []byte(body)...)
+	out = append(out, '\r', '\n')
+	return out
+}
+
+func marshalBlobError(msg string) []byte {
+	out := []byte{RespBlobError}
+	out = append(out, []byte(strconv.Itoa(len(msg)))...)
+	out = append(out, '\r', '\n')
+	out = append(out, []byte(msg)...)
+	out = append(out, '\r', '\n')
+	return out
+}
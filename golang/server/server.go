@@ -7,18 +7,22 @@ import (
 	"net"
 	"strings"
 
+	"github.com/afoley587/redis-lite/cluster"
 	"github.com/afoley587/redis-lite/persistence"
 	"github.com/afoley587/redis-lite/resp"
 	"github.com/afoley587/redis-lite/store"
 )
 
 type Server struct {
-	addr  string
-	proto string
+	addr    string
+	proto   string
+	cluster *cluster.Cluster
 }
 
-func NewServer(addr string, proto string) *Server {
-	return &Server{addr, proto}
+// NewServer creates a Server listening on addr. clus may be nil, in which
+// case the server runs standalone with no slot routing.
+func NewServer(addr string, proto string, clus *cluster.Cluster) *Server {
+	return &Server{addr, proto, clus}
 }
 
 func (s *Server) ListenAndServe(aof *persistence.Aof) error {
@@ -37,48 +41,84 @@ func (s *Server) ListenAndServe(aof *persistence.Aof) error {
 			continue
 		}
 
-		go handleConnection(conn, aof)
+		go handleConnection(conn, aof, s.cluster)
 	}
 }
 
-func handleConnection(conn net.Conn, aof *persistence.Aof) {
-	defer conn.Close()
-	rw := resp.NewRespWriter(conn) // See part 2
+func handleConnection(netConn net.Conn, aof *persistence.Aof, clus *cluster.Cluster) {
+	defer netConn.Close()
+	c := resp.NewConn(netConn) // See part 2
+	r := resp.NewResp(netConn) // one streaming reader, reused for the connection's lifetime
 
 	for {
-		buf := make([]byte, 1024)
-		n, err := conn.Read(buf)
-		if err != nil {
-			if err == io.EOF {
-				log.Printf("Client disconnected: %v", conn.RemoteAddr())
-				return
-			}
-			log.Printf("Read error: %v", err)
-			continue
+		done := handleOneCommand(netConn, r, c, aof, clus)
+		if done {
+			return
 		}
+	}
+}
 
-		r := resp.NewResp(buf[:n]) // See part 2
-		cmd, err := r.Read()
-		if err != nil || len(cmd.Array) == 0 {
-			log.Printf("Invalid command: %v", err)
-			rw.Write(resp.RespValue{Type: resp.RespError, String: "ERR invalid command"})
-			continue
+// handleOneCommand reads and executes a single command, returning true when
+// the connection should be closed. The recover here keeps a panic raised
+// while reading or handling one command scoped to this one connection
+// instead of taking down every other connection sharing the process.
+func handleOneCommand(netConn net.Conn, r *resp.Resp, c resp.Conn, aof *persistence.Aof, clus *cluster.Cluster) (done bool) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("Recovered from panic handling %v: %v", netConn.RemoteAddr(), rec)
+			done = true
 		}
+	}()
 
-		commandName := strings.ToUpper(cmd.Array[0].Bulk)
-		handler, ok := store.Handlers[commandName] // See part 3
-		if !ok {
-			log.Printf("Unknown command: %s", commandName)
-			rw.Write(resp.RespValue{Type: resp.RespError, String: "ERR unknown command"})
-			continue
+	cmd, err := r.ReadCommand()
+	if err != nil {
+		if err == io.EOF {
+			log.Printf("Client disconnected: %v", netConn.RemoteAddr())
+			return true
 		}
+		log.Printf("Read error: %v", err)
+		return true
+	}
 
-		// Write to AOF before executing command
-		if err := aof.Write(cmd); err != nil { // See part 3
-			log.Printf("AOF write failed: %v", err)
+	if len(cmd.Args) == 0 {
+		log.Printf("Invalid command: empty args")
+		c.WriteError("ERR invalid command")
+		return false
+	}
+
+	commandName := strings.ToUpper(string(cmd.Args[0]))
+	handler, ok := store.Handlers[commandName] // See part 3
+	if !ok {
+		log.Printf("Unknown command: %s", commandName)
+		c.WriteError("ERR unknown command")
+		return false
+	}
+
+	if store.IsSubscribed(c) && store.RequiresSubscriberMode(commandName) {
+		c.WriteError(fmt.Sprintf(
+			"ERR Can't execute '%s': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT are allowed in this context",
+			strings.ToLower(commandName)))
+		return false
+	}
+
+	if clus != nil {
+		if key, ok := cluster.KeyOf(commandName, cmd.Args[1:]); ok {
+			if owner := clus.OwnerOf(key); owner.ID != clus.Self().ID {
+				c.WriteError(fmt.Sprintf("MOVED %d %s", cluster.KeySlot(key), owner.Addr))
+				return false
+			}
 		}
+	}
 
-		response := handler(cmd.Array[1:])
-		rw.Write(response) // See part 2
+	handler(c, cmd)
+
+	// Log to the AOF after the command has already taken effect, so a
+	// concurrent Aof.Rewrite can never observe a command as durable in the
+	// old file without also seeing its effect in the keyspace snapshot it
+	// rewrites from (see Aof.Rewrite's doc comment).
+	if err := aof.Write(cmd); err != nil { // See part 3
+		log.Printf("AOF write failed: %v", err)
 	}
+
+	return false
 }
@@ -10,3 +10,17 @@ var (
 	cache     = make(map[string]resp.RespValue)
 	cacheLock = sync.RWMutex{}
 )
+
+// Snapshot returns a point-in-time copy of the keyspace. It exists so
+// persistence (AOF rewrite, RDB-style save) can walk the whole keyspace
+// without holding cacheLock for the entire duration of a slow dump.
+func Snapshot() map[string]resp.RespValue {
+	cacheLock.RLock()
+	defer cacheLock.RUnlock()
+
+	snap := make(map[string]resp.RespValue, len(cache))
+	for k, v := range cache {
+		snap[k] = v
+	}
+	return snap
+}
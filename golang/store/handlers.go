@@ -1,65 +1,88 @@
 package store
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/afoley587/redis-lite/resp"
 )
 
-var Handlers = map[string]func([]resp.RespValue) resp.RespValue{
-	"PING": ping,
-	"GET":  get,
-	"SET":  set,
-	"DEL":  del,
+var Handlers = map[string]func(conn resp.Conn, cmd resp.Command){
+	"PING":  ping,
+	"GET":   get,
+	"SET":   set,
+	"DEL":   del,
+	"HELLO": hello,
+	"QUIT":  quit,
+
+	"SUBSCRIBE":    subscribe,
+	"UNSUBSCRIBE":  unsubscribe,
+	"PSUBSCRIBE":   psubscribe,
+	"PUNSUBSCRIBE": punsubscribe,
+	"PUBLISH":      publish,
+	"PUBSUB":       pubsubCommand,
 }
 
-func ping(args []resp.RespValue) resp.RespValue {
+func ping(conn resp.Conn, cmd resp.Command) {
+	args := cmd.Args[1:]
 	if len(args) == 0 {
-		return resp.NewSimpleString("PONG")
+		conn.WriteString("PONG")
+		return
 	}
 
 	values := make([]string, 0, len(args))
 	for _, arg := range args {
-		values = append(values, arg.Bulk)
+		values = append(values, string(arg))
 	}
 
-	return resp.NewBulkString(strings.Join(values, " "))
+	conn.WriteBulk([]byte(strings.Join(values, " ")))
 }
 
-func set(args []resp.RespValue) resp.RespValue {
+func set(conn resp.Conn, cmd resp.Command) {
+	args := cmd.Args[1:]
 	if len(args) != 2 {
-		return resp.NewError("ERR wrong number of arguments for 'SET'")
+		conn.WriteError("ERR wrong number of arguments for 'SET'")
+		return
 	}
 
-	key := strings.TrimSpace(args[0].Bulk)
-	value := args[1]
+	key := strings.TrimSpace(string(args[0]))
+	value := resp.NewBulkString(string(args[1]))
 
 	cacheLock.Lock()
 	cache[key] = value
 	cacheLock.Unlock()
 
-	return resp.NewSimpleString("OK")
+	conn.WriteString("OK")
 }
 
-func get(args []resp.RespValue) resp.RespValue {
+func get(conn resp.Conn, cmd resp.Command) {
+	args := cmd.Args[1:]
 	if len(args) != 1 {
-		return resp.NewError("ERR wrong number of arguments for 'GET'")
+		conn.WriteError("ERR wrong number of arguments for 'GET'")
+		return
 	}
 
-	key := strings.TrimSpace(args[0].Bulk)
+	key := strings.TrimSpace(string(args[0]))
 
 	cacheLock.RLock()
-	defer cacheLock.RUnlock()
+	val, ok := cache[key]
+	cacheLock.RUnlock()
 
-	if val, ok := cache[key]; ok {
-		return val
+	if !ok {
+		conn.WriteNull()
+		return
 	}
-	return resp.NewNull()
+
+	b := resp.NewReplyBuilder(conn.Proto())
+	writeValue(b, val)
+	b.Flush(conn)
 }
 
-func del(args []resp.RespValue) resp.RespValue {
+func del(conn resp.Conn, cmd resp.Command) {
+	args := cmd.Args[1:]
 	if len(args) == 0 {
-		return resp.NewError("ERR wrong number of arguments for 'DEL'")
+		conn.WriteError("ERR wrong number of arguments for 'DEL'")
+		return
 	}
 
 	cacheLock.Lock()
@@ -67,11 +90,85 @@ func del(args []resp.RespValue) resp.RespValue {
 
 	deleted := 0
 	for _, arg := range args {
-		key := strings.TrimSpace(arg.Bulk)
+		key := strings.TrimSpace(string(arg))
 		if _, ok := cache[key]; ok {
 			delete(cache, key)
 			deleted++
 		}
 	}
-	return resp.NewInteger(deleted)
+	conn.WriteInt(deleted)
+}
+
+func quit(conn resp.Conn, cmd resp.Command) {
+	conn.WriteString("OK")
+}
+
+// hello implements HELLO [protover [AUTH user pass] [SETNAME name]]. It
+// negotiates the RESP protocol version for the connection; AUTH/SETNAME are
+// accepted but ignored since this server has no ACL concept yet.
+func hello(conn resp.Conn, cmd resp.Command) {
+	args := cmd.Args[1:]
+	proto := conn.Proto()
+
+	if len(args) > 0 {
+		requested, err := strconv.Atoi(string(args[0]))
+		if err != nil || (requested != resp.ProtoRESP2 && requested != resp.ProtoRESP3) {
+			conn.WriteError("NOPROTO unsupported protocol version")
+			return
+		}
+		proto = requested
+		args = args[1:]
+	}
+
+	for len(args) > 0 {
+		switch strings.ToUpper(string(args[0])) {
+		case "AUTH":
+			if len(args) < 3 {
+				conn.WriteError("ERR syntax error in HELLO")
+				return
+			}
+			args = args[3:]
+		case "SETNAME":
+			if len(args) < 2 {
+				conn.WriteError("ERR syntax error in HELLO")
+				return
+			}
+			args = args[2:]
+		default:
+			conn.WriteError("ERR syntax error in HELLO")
+			return
+		}
+	}
+
+	conn.SetProto(proto)
+
+	b := resp.NewReplyBuilder(proto)
+	b.WriteMap(3)
+	b.WriteBulk([]byte("proto"))
+	b.WriteInt(proto)
+	b.WriteBulk([]byte("mode"))
+	b.WriteString("standalone")
+	b.WriteBulk([]byte("role"))
+	b.WriteString("master")
+	b.Flush(conn)
+}
+
+// writeValue appends a value fetched from the cache to b, recursing into
+// arrays rather than marshaling the whole RespValue up front.
+func writeValue(b *resp.ReplyBuilder, val resp.RespValue) {
+	switch val.Type {
+	case resp.RespString:
+		b.WriteString(val.String)
+	case resp.RespInteger:
+		b.WriteInt(val.Integer)
+	case resp.RespBulk:
+		b.WriteBulk([]byte(val.Bulk))
+	case resp.RespArray:
+		b.WriteArray(len(val.Array))
+		for _, v := range val.Array {
+			writeValue(b, v)
+		}
+	default:
+		b.WriteNull()
+	}
 }
@@ -0,0 +1,229 @@
+package store
+
+import (
+	"strings"
+
+	"github.com/afoley587/redis-lite/pubsub"
+	"github.com/afoley587/redis-lite/resp"
+)
+
+var broker = pubsub.NewBroker()
+
+// allowedWhileSubscribed are the only commands a connection may run once it
+// has any channel or pattern subscription, matching real Redis's restricted
+// pub/sub mode.
+var allowedWhileSubscribed = map[string]bool{
+	"SUBSCRIBE":    true,
+	"UNSUBSCRIBE":  true,
+	"PSUBSCRIBE":   true,
+	"PUNSUBSCRIBE": true,
+	"PING":         true,
+	"QUIT":         true,
+}
+
+// RequiresSubscriberMode reports whether commandName is NOT one of the
+// handful of commands allowed on an already-subscribed connection.
+func RequiresSubscriberMode(commandName string) bool {
+	return !allowedWhileSubscribed[commandName]
+}
+
+// subscriberState is stashed in a connection's Conn context once it
+// subscribes to anything, so repeated (un)subscribe calls can report
+// accurate counts and IsSubscribed can tell whether the connection is
+// currently restricted to pub/sub commands.
+type subscriberState struct {
+	channels map[string]bool
+	patterns map[string]bool
+}
+
+// IsSubscribed reports whether conn currently has any channel or pattern
+// subscriptions.
+func IsSubscribed(conn resp.Conn) bool {
+	state, ok := conn.Context().(*subscriberState)
+	return ok && state != nil && (len(state.channels) > 0 || len(state.patterns) > 0)
+}
+
+func subscriberStateFor(conn resp.Conn) *subscriberState {
+	state, ok := conn.Context().(*subscriberState)
+	if !ok || state == nil {
+		state = &subscriberState{channels: make(map[string]bool), patterns: make(map[string]bool)}
+		conn.SetContext(state)
+	}
+	return state
+}
+
+func subscriptionCount(state *subscriberState) int {
+	return len(state.channels) + len(state.patterns)
+}
+
+func subscribe(conn resp.Conn, cmd resp.Command) {
+	args := cmd.Args[1:]
+	if len(args) == 0 {
+		conn.WriteError("ERR wrong number of arguments for 'SUBSCRIBE'")
+		return
+	}
+
+	state := subscriberStateFor(conn)
+	b := resp.NewReplyBuilder(conn.Proto())
+	for _, arg := range args {
+		channel := string(arg)
+		broker.Subscribe(conn, channel)
+		state.channels[channel] = true
+
+		b.WriteArray(3)
+		b.WriteBulk([]byte("subscribe"))
+		b.WriteBulk([]byte(channel))
+		b.WriteInt(subscriptionCount(state))
+	}
+	b.Flush(conn)
+}
+
+func unsubscribe(conn resp.Conn, cmd resp.Command) {
+	state := subscriberStateFor(conn)
+
+	channels := argsOrKeys(cmd.Args[1:], state.channels)
+	broker.Unsubscribe(conn, channels...)
+	for _, ch := range channels {
+		delete(state.channels, ch)
+	}
+
+	b := resp.NewReplyBuilder(conn.Proto())
+	if len(channels) == 0 {
+		b.WriteArray(3)
+		b.WriteBulk([]byte("unsubscribe"))
+		b.WriteNull()
+		b.WriteInt(subscriptionCount(state))
+		b.Flush(conn)
+		return
+	}
+
+	for _, ch := range channels {
+		b.WriteArray(3)
+		b.WriteBulk([]byte("unsubscribe"))
+		b.WriteBulk([]byte(ch))
+		b.WriteInt(subscriptionCount(state))
+	}
+	b.Flush(conn)
+}
+
+func psubscribe(conn resp.Conn, cmd resp.Command) {
+	args := cmd.Args[1:]
+	if len(args) == 0 {
+		conn.WriteError("ERR wrong number of arguments for 'PSUBSCRIBE'")
+		return
+	}
+
+	state := subscriberStateFor(conn)
+	b := resp.NewReplyBuilder(conn.Proto())
+	for _, arg := range args {
+		pattern := string(arg)
+		broker.PSubscribe(conn, pattern)
+		state.patterns[pattern] = true
+
+		b.WriteArray(3)
+		b.WriteBulk([]byte("psubscribe"))
+		b.WriteBulk([]byte(pattern))
+		b.WriteInt(subscriptionCount(state))
+	}
+	b.Flush(conn)
+}
+
+func punsubscribe(conn resp.Conn, cmd resp.Command) {
+	state := subscriberStateFor(conn)
+
+	patterns := argsOrKeys(cmd.Args[1:], state.patterns)
+	broker.PUnsubscribe(conn, patterns...)
+	for _, pat := range patterns {
+		delete(state.patterns, pat)
+	}
+
+	b := resp.NewReplyBuilder(conn.Proto())
+	if len(patterns) == 0 {
+		b.WriteArray(3)
+		b.WriteBulk([]byte("punsubscribe"))
+		b.WriteNull()
+		b.WriteInt(subscriptionCount(state))
+		b.Flush(conn)
+		return
+	}
+
+	for _, pat := range patterns {
+		b.WriteArray(3)
+		b.WriteBulk([]byte("punsubscribe"))
+		b.WriteBulk([]byte(pat))
+		b.WriteInt(subscriptionCount(state))
+	}
+	b.Flush(conn)
+}
+
+// argsOrKeys converts args to strings, or (if args is empty) returns the
+// keys of fallback -- used so a bare UNSUBSCRIBE/PUNSUBSCRIBE means "all of
+// the caller's current subscriptions".
+func argsOrKeys(args [][]byte, fallback map[string]bool) []string {
+	if len(args) > 0 {
+		names := make([]string, len(args))
+		for i, a := range args {
+			names[i] = string(a)
+		}
+		return names
+	}
+
+	names := make([]string, 0, len(fallback))
+	for name := range fallback {
+		names = append(names, name)
+	}
+	return names
+}
+
+func publish(conn resp.Conn, cmd resp.Command) {
+	args := cmd.Args[1:]
+	if len(args) != 2 {
+		conn.WriteError("ERR wrong number of arguments for 'PUBLISH'")
+		return
+	}
+
+	conn.WriteInt(broker.Publish(string(args[0]), string(args[1])))
+}
+
+func pubsubCommand(conn resp.Conn, cmd resp.Command) {
+	args := cmd.Args[1:]
+	if len(args) == 0 {
+		conn.WriteError("ERR wrong number of arguments for 'PUBSUB'")
+		return
+	}
+
+	switch strings.ToUpper(string(args[0])) {
+	case "CHANNELS":
+		pattern := ""
+		if len(args) > 1 {
+			pattern = string(args[1])
+		}
+		channels := broker.Channels(pattern)
+		b := resp.NewReplyBuilder(conn.Proto())
+		b.WriteArray(len(channels))
+		for _, ch := range channels {
+			b.WriteBulk([]byte(ch))
+		}
+		b.Flush(conn)
+
+	case "NUMSUB":
+		channels := make([]string, len(args)-1)
+		for i, a := range args[1:] {
+			channels[i] = string(a)
+		}
+		counts := broker.NumSub(channels)
+		b := resp.NewReplyBuilder(conn.Proto())
+		b.WriteArray(len(channels) * 2)
+		for _, ch := range channels {
+			b.WriteBulk([]byte(ch))
+			b.WriteInt(counts[ch])
+		}
+		b.Flush(conn)
+
+	case "NUMPAT":
+		conn.WriteInt(broker.NumPat())
+
+	default:
+		conn.WriteError("ERR unknown PUBSUB subcommand")
+	}
+}